@@ -1,13 +1,88 @@
 package config
 
+// DefaultShutdownTimeoutSecond 未配置ShutdownTimeoutSecond 或配置不为正数时使用的等待时间。
+const DefaultShutdownTimeoutSecond = 10
+
 // Config 后端配置。
 type Config struct {
 	ListenAddr string `json:"listen_addr"`
+	// ShutdownTimeoutSecond 收到退出信号后，等待正在处理的请求完成的最长时间，不为正数时使用DefaultShutdownTimeoutSecond。
+	ShutdownTimeoutSecond int `json:"shutdown_timeout_second"`
+	// MaxRequestBodyBytes 请求体的最大字节数。
+	MaxRequestBodyBytes int64 `json:"max_request_body_bytes"`
+	// CORS 跨域请求配置。
+	CORS CORSConfig `json:"cors"`
+	// Account 账号相关配置。
+	Account AccountConfig `json:"account"`
+	// SMS 短信验证码相关配置。
+	SMS SMSConfig `json:"sms"`
+	// Cookie 登录cookie 配置。
+	Cookie CookieConfig `json:"cookie"`
+}
+
+// CookieConfig 登录cookie 配置。
+type CookieConfig struct {
+	// Domain cookie 所属的域名，为空时为当前请求的域名。
+	Domain string `json:"domain"`
+	// Secure 是否只在HTTPS 请求中携带cookie。
+	Secure bool `json:"secure"`
+	// SameSite cookie 的SameSite 属性，可选 "lax"、"strict"、"none"，为空时不设置。
+	SameSite string `json:"same_site"`
+	// MaxAgeSecond cookie 的有效期，为0时为会话cookie。
+	MaxAgeSecond int `json:"max_age_second"`
+}
+
+// AccountConfig 账号相关配置。
+type AccountConfig struct {
+	// DeactivateCooldownHour 账号注销后，该手机号不能再次登录的时长。
+	DeactivateCooldownHour int `json:"deactivate_cooldown_hour"`
+}
+
+// CORSConfig 跨域请求（CORS）配置。
+type CORSConfig struct {
+	// AllowOrigins 允许跨域访问的来源，"*"表示允许所有来源。
+	AllowOrigins []string `json:"allow_origins"`
+	// AllowMethods 允许的HTTP方法。
+	AllowMethods []string `json:"allow_methods"`
+	// AllowHeaders 允许携带的请求头。
+	AllowHeaders []string `json:"allow_headers"`
+	// AllowCredentials 是否允许携带cookie等凭证。
+	AllowCredentials bool `json:"allow_credentials"`
+	// MaxAgeSecond 预检请求结果的缓存时间。
+	MaxAgeSecond int `json:"max_age_second"`
+}
+
+// SMSConfig 短信验证码相关配置。
+type SMSConfig struct {
+	// BlockedPrefixes 禁止发送验证码的手机号段，如虚拟运营商号段。
+	BlockedPrefixes []string `json:"blocked_prefixes"`
+	// RequireCaptcha 请求验证码时是否需要先通过图形验证码校验。
+	RequireCaptcha bool `json:"require_captcha"`
 }
 
 // NewSample 返回样例配置。
 func NewSample() *Config {
 	return &Config{
-		ListenAddr: ":8080",
+		ListenAddr:            ":8080",
+		ShutdownTimeoutSecond: DefaultShutdownTimeoutSecond,
+		MaxRequestBodyBytes:   1 << 20,
+		CORS: CORSConfig{
+			AllowOrigins:     []string{"https://qlive.qiniu.com"},
+			AllowMethods:     []string{"GET", "POST", "DELETE", "OPTIONS"},
+			AllowHeaders:     []string{"Content-Type", "Authorization"},
+			AllowCredentials: true,
+			MaxAgeSecond:     600,
+		},
+		Account: AccountConfig{
+			DeactivateCooldownHour: 24 * 30,
+		},
+		SMS: SMSConfig{
+			BlockedPrefixes: []string{"170", "171"},
+		},
+		Cookie: CookieConfig{
+			Domain:   "qlive.qiniu.com",
+			Secure:   true,
+			SameSite: "lax",
+		},
 	}
 }
@@ -10,12 +10,16 @@ import (
 type HTTPError struct {
 	// HTTP 状态码。
 	Code int `json:"code"`
+	// ErrorCode 服务内部错误码，用于客户端区分同一HTTP 状态码下的不同错误。
+	ErrorCode int `json:"errorCode,omitempty"`
 	// 请求ID。
 	RequestID string `json:"requestID"`
 	// Summary
 	Summary string `json:"summary"`
 	// Message 错误消息。
 	Message string `json:"message"`
+	// Fields 参数校验失败的字段及其未通过的校验规则。
+	Fields map[string]string `json:"fields,omitempty"`
 }
 
 // WithMessage 为HTTP错误添加详细消息。
@@ -30,6 +34,15 @@ func (e *HTTPError) WithMessagef(format string, args ...interface{}) *HTTPError
 	return e.WithMessage(msg)
 }
 
+// WithField 记录一个未通过校验的字段。
+func (e *HTTPError) WithField(field string, rule string) *HTTPError {
+	if e.Fields == nil {
+		e.Fields = map[string]string{}
+	}
+	e.Fields[field] = rule
+	return e
+}
+
 func (e *HTTPError) Error() string {
 	buf, err := json.Marshal(e)
 	if err != nil {
@@ -54,6 +67,14 @@ func NewHTTPErrorUnauthorized() *HTTPError {
 	}
 }
 
+// NewHTTPErrorForbidden 一般的HTTP forbidden 错误。
+func NewHTTPErrorForbidden() *HTTPError {
+	return &HTTPError{
+		Code:    http.StatusForbidden,
+		Summary: "forbidden",
+	}
+}
+
 // NewHTTPErrorNotFound 一般的HTTP not found 错误。
 func NewHTTPErrorNotFound() *HTTPError {
 	return &HTTPError{
@@ -61,3 +82,35 @@ func NewHTTPErrorNotFound() *HTTPError {
 		Summary: "not found",
 	}
 }
+
+// NewHTTPErrorRequestEntityTooLarge 请求体过大的错误。
+func NewHTTPErrorRequestEntityTooLarge() *HTTPError {
+	return &HTTPError{
+		Code:    http.StatusRequestEntityTooLarge,
+		Summary: "request entity too large",
+	}
+}
+
+// NewHTTPErrorUnprocessableEntity 请求参数未通过校验的错误。
+func NewHTTPErrorUnprocessableEntity() *HTTPError {
+	return &HTTPError{
+		Code:    http.StatusUnprocessableEntity,
+		Summary: "unprocessable entity",
+	}
+}
+
+// NewHTTPErrorConflict 一般的HTTP conflict 错误。
+func NewHTTPErrorConflict() *HTTPError {
+	return &HTTPError{
+		Code:    http.StatusConflict,
+		Summary: "conflict",
+	}
+}
+
+// NewHTTPErrorInternal 一般的HTTP internal server error 错误。
+func NewHTTPErrorInternal() *HTTPError {
+	return &HTTPError{
+		Code:    http.StatusInternalServerError,
+		Summary: "internal server error",
+	}
+}
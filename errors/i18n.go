@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// LanguageZhCN 简体中文。
+	LanguageZhCN = "zh-CN"
+	// LanguageEnUS 英文。
+	LanguageEnUS = "en-US"
+	// DefaultLanguage 未指定或不支持请求的语言时使用的语言。
+	DefaultLanguage = LanguageZhCN
+)
+
+// summaryCatalog 按语言与错误码索引的错误摘要。
+var summaryCatalog = map[string]map[int]string{
+	LanguageZhCN: {
+		http.StatusBadRequest:            "请求错误",
+		http.StatusUnauthorized:          "未授权",
+		http.StatusForbidden:             "禁止访问",
+		http.StatusNotFound:              "资源不存在",
+		http.StatusConflict:              "资源冲突",
+		http.StatusRequestEntityTooLarge: "请求体过大",
+		http.StatusUnprocessableEntity:   "参数校验失败",
+		http.StatusInternalServerError:   "服务器内部错误",
+	},
+	LanguageEnUS: {
+		http.StatusBadRequest:            "bad request",
+		http.StatusUnauthorized:          "unauthorized",
+		http.StatusForbidden:             "forbidden",
+		http.StatusNotFound:              "not found",
+		http.StatusConflict:              "conflict",
+		http.StatusRequestEntityTooLarge: "request entity too large",
+		http.StatusUnprocessableEntity:   "unprocessable entity",
+		http.StatusInternalServerError:   "internal server error",
+	},
+}
+
+// ParseAcceptLanguage 根据Accept-Language 请求头选择q 值最高的支持语言，q 值相同时取先出现的，
+// q=0 表示不接受该语言。没有支持的语言时返回默认语言。
+func ParseAcceptLanguage(header string) string {
+	lang := DefaultLanguage
+	bestQ := 0.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				parsed, err := strconv.ParseFloat(param[len("q="):], 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		supported := matchLanguage(strings.ToLower(strings.TrimSpace(fields[0])))
+		if supported != "" && q > bestQ {
+			lang, bestQ = supported, q
+		}
+	}
+	return lang
+}
+
+// matchLanguage 返回语言标签对应的支持语言，不支持时返回空字符串。
+func matchLanguage(tag string) string {
+	switch {
+	case strings.HasPrefix(tag, "zh"):
+		return LanguageZhCN
+	case strings.HasPrefix(tag, "en"):
+		return LanguageEnUS
+	}
+	return ""
+}
+
+// Localize 将错误摘要替换为指定语言的版本。目录中没有对应条目时保留原摘要。
+func (e *HTTPError) Localize(lang string) *HTTPError {
+	if summary, ok := summaryCatalog[lang][e.Code]; ok {
+		e.Summary = summary
+	}
+	return e
+}
@@ -0,0 +1,37 @@
+package errors
+
+import "testing"
+
+func TestParseAcceptLanguage(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   string
+	}{
+		{"", LanguageZhCN},
+		{"zh-CN", LanguageZhCN},
+		{"en-US,en;q=0.9", LanguageEnUS},
+		{"zh-CN,zh;q=0.9,en;q=0.8", LanguageZhCN},
+		{"en;q=0.1, zh-CN;q=0.9", LanguageZhCN},
+		{"fr-FR, en;q=0.5", LanguageEnUS},
+		{"en;q=0, zh;q=0.2", LanguageZhCN},
+		{"en;q=0", LanguageZhCN},
+		{"fr, de", LanguageZhCN},
+	}
+	for _, tc := range testCases {
+		if got := ParseAcceptLanguage(tc.header); got != tc.want {
+			t.Errorf("ParseAcceptLanguage(%q) = %s, want %s", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	if got := NewHTTPErrorNotFound().Localize(LanguageZhCN).Summary; got != "资源不存在" {
+		t.Errorf("expected zh-CN summary, got %q", got)
+	}
+	if got := NewHTTPErrorNotFound().Localize(LanguageEnUS).Summary; got != "not found" {
+		t.Errorf("expected en-US summary, got %q", got)
+	}
+	if got := NewHTTPErrorNotFound().Localize("fr-FR").Summary; got != "not found" {
+		t.Errorf("expected original summary for unsupported language, got %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package errors
+
+import "fmt"
+
+// 服务内部错误码。
+const (
+	// ServerErrorBadRequest 传入服务的参数错误。
+	ServerErrorBadRequest = 400001
+	// ServerErrorWrongCaptcha 图形验证码错误。
+	ServerErrorWrongCaptcha = 400002
+	// ServerErrorWrongSMSCode 短信验证码错误。
+	ServerErrorWrongSMSCode = 401001
+	// ServerErrorInvalidToken 登录token 无效。
+	ServerErrorInvalidToken = 401002
+	// ServerErrorNotLoggedIn 请求未携带登录token。
+	ServerErrorNotLoggedIn = 401003
+	// ServerErrorPhoneNumberBlocked 手机号属于被屏蔽的号段。
+	ServerErrorPhoneNumberBlocked = 403001
+	// ServerErrorUserNotFound 用户不存在。
+	ServerErrorUserNotFound = 404001
+	// ServerErrorUserConflict 用户ID或手机号已被占用。
+	ServerErrorUserConflict = 409001
+	// ServerErrorRequestBodyTooLarge 请求体超过大小限制。
+	ServerErrorRequestBodyTooLarge = 413001
+)
+
+var serverErrorMessages = map[int]string{
+	ServerErrorBadRequest:          "bad request",
+	ServerErrorWrongCaptcha:        "wrong captcha",
+	ServerErrorWrongSMSCode:        "wrong sms code",
+	ServerErrorInvalidToken:        "invalid token",
+	ServerErrorNotLoggedIn:         "not logged in",
+	ServerErrorPhoneNumberBlocked:  "phone number blocked",
+	ServerErrorUserNotFound:        "user not found",
+	ServerErrorUserConflict:        "user conflict",
+	ServerErrorRequestBodyTooLarge: "request body too large",
+}
+
+// ServerError 服务（账号、短信、鉴权等）内部返回的错误。
+type ServerError struct {
+	Code    int
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// NewServerError 根据错误码创建服务内部错误。
+func NewServerError(code int) *ServerError {
+	return &ServerError{
+		Code:    code,
+		Message: serverErrorMessages[code],
+	}
+}
+
+// IsServerError 判断err 是否为指定错误码的服务内部错误。
+func IsServerError(err error, code int) bool {
+	serverErr, ok := err.(*ServerError)
+	return ok && serverErr.Code == code
+}
+
+// NewHTTPErrorByServerError 将服务内部错误转换为对应的HTTP 错误，未知错误转换为500。
+func NewHTTPErrorByServerError(err error) *HTTPError {
+	serverErr, ok := err.(*ServerError)
+	if !ok {
+		return NewHTTPErrorInternal().WithMessage(err.Error())
+	}
+	var httpErr *HTTPError
+	switch serverErr.Code {
+	case ServerErrorBadRequest, ServerErrorWrongCaptcha:
+		httpErr = NewHTTPErrorBadRequest()
+	case ServerErrorWrongSMSCode, ServerErrorInvalidToken, ServerErrorNotLoggedIn:
+		httpErr = NewHTTPErrorUnauthorized()
+	case ServerErrorPhoneNumberBlocked:
+		httpErr = NewHTTPErrorForbidden()
+	case ServerErrorUserNotFound:
+		httpErr = NewHTTPErrorNotFound()
+	case ServerErrorUserConflict:
+		httpErr = NewHTTPErrorConflict()
+	case ServerErrorRequestBodyTooLarge:
+		httpErr = NewHTTPErrorRequestEntityTooLarge()
+	default:
+		httpErr = NewHTTPErrorInternal()
+	}
+	httpErr.ErrorCode = serverErr.Code
+	return httpErr.WithMessage(serverErr.Message)
+}
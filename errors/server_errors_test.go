@@ -0,0 +1,32 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNewHTTPErrorByServerError(t *testing.T) {
+	for code, message := range serverErrorMessages {
+		httpErr := NewHTTPErrorByServerError(NewServerError(code))
+		if httpErr.Code != code/1000 {
+			t.Errorf("server error %d: expected HTTP code %d, got %d", code, code/1000, httpErr.Code)
+		}
+		if httpErr.Code == http.StatusInternalServerError {
+			t.Errorf("server error %d is not mapped to an HTTP error", code)
+		}
+		if httpErr.ErrorCode != code {
+			t.Errorf("server error %d: expected error code %d, got %d", code, code, httpErr.ErrorCode)
+		}
+		if httpErr.Message != message {
+			t.Errorf("server error %d: expected message %q, got %q", code, message, httpErr.Message)
+		}
+	}
+}
+
+func TestNewHTTPErrorByServerErrorUnknown(t *testing.T) {
+	httpErr := NewHTTPErrorByServerError(fmt.Errorf("boom"))
+	if httpErr.Code != http.StatusInternalServerError || httpErr.ErrorCode != 0 {
+		t.Errorf("expected plain 500 for unknown error, got %+v", httpErr)
+	}
+}
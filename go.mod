@@ -4,5 +4,6 @@ go 1.15
 
 require (
 	github.com/gin-gonic/gin v1.6.3
+	github.com/go-playground/validator/v10 v10.2.0
 	github.com/satori/go.uuid v1.2.0
 )
@@ -1,14 +1,18 @@
 package handler
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	uuid "github.com/satori/go.uuid"
 
+	"github.com/qrtc/qlive/config"
 	"github.com/qrtc/qlive/errors"
 	"github.com/qrtc/qlive/protocol"
+	"github.com/qrtc/qlive/reqctx"
 )
 
 // AccountInterface 获取账号信息的接口。
@@ -17,35 +21,126 @@ type AccountInterface interface {
 	GetAccountByID(id string) (*protocol.Account, error)
 	CreateAccount(account *protocol.Account) error
 	UpdateAccount(id string, account *protocol.Account) (*protocol.Account, error)
+	// RecordLogin 只更新账号的最近登录IP与时间，不覆盖其他资料。
+	RecordLogin(id string, ip string, loginTime time.Time) (*protocol.Account, error)
+	// DeactivateAccount 将账号标记为已注销，保留账号记录，并使已签发的登录token 失效。
+	DeactivateAccount(id string) error
+	// ReactivateAccount 恢复已注销的账号并清空原有资料，token 版本保持不变。
+	ReactivateAccount(id string) (*protocol.Account, error)
+	// RotateTokenVersion 更新账号的登录token 版本，使之前签发的所有token 失效。
+	RotateTokenVersion(id string) error
+	// ValidateTokenVersion 检查账号未注销且token 版本与账号当前的版本一致，检查与读取在同一次调用内完成。
+	ValidateTokenVersion(id string, version int) error
 }
 
 // SMSCodeInterface 发送短信验证码并记录的接口。
 type SMSCodeInterface interface {
 	Send(phoneNumber string) (err error)
+	// SendVoice 通过语音电话发送验证码，与短信验证码共用记录，均由Validate 校验。
+	SendVoice(phoneNumber string) (err error)
 	Validate(phoneNumber string, smsCode string) (err error)
 }
 
+// CaptchaInterface 校验图形验证码的接口。
+type CaptchaInterface interface {
+	Verify(captcha string) (err error)
+}
+
 // AccountHandler 处理与账号相关的请求：登录、注册、退出、修改账号信息等
 type AccountHandler struct {
 	Account AccountInterface
 	SMSCode SMSCodeInterface
+	Captcha CaptchaInterface
+	// RequireCaptcha 发送短信验证码前是否需要校验图形验证码。
+	RequireCaptcha bool
+	// BlockedPrefixes 禁止发送验证码的手机号段，短信与语音验证码都适用。
+	BlockedPrefixes []string
+	// DeactivateCooldown 账号注销后，该手机号不能再次登录的时长。
+	DeactivateCooldown time.Duration
+	// Cookie 登录cookie 的设置。
+	Cookie config.CookieConfig
 }
 
 // GetSMSCode 获取短信验证码。
 func (h *AccountHandler) GetSMSCode(c *gin.Context) {
-	phoneNumber, ok := c.GetQuery("number")
+	rawPhoneNumber, ok := c.GetQuery("number")
 	if !ok {
-		c.JSON(http.StatusBadRequest, "empty phone number")
+		AbortWithHTTPError(c, errors.NewHTTPErrorBadRequest().WithMessage("empty phone number"))
+		return
+	}
+	phoneNumber, ok := normalizePhoneNumberOrError(c, "number", rawPhoneNumber)
+	if !ok || !h.checkBlockedPrefix(c, phoneNumber) {
+		return
+	}
+	if !h.verifyCaptcha(c, c.Query("captcha")) {
 		return
 	}
 	err := h.SMSCode.Send(phoneNumber)
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(err))
 		return
 	}
 	c.JSON(http.StatusOK, nil)
 }
 
+// SendVoiceCode 通过语音电话发送验证码，用于收不到短信的用户。验证码与短信验证码使用同样的方式校验。
+func (h *AccountHandler) SendVoiceCode(c *gin.Context) {
+	args := protocol.SendVoiceCodeArgs{}
+	if !bindJSONOrError(c, &args) {
+		return
+	}
+	phoneNumber, ok := normalizePhoneNumberOrError(c, "phoneNumber", args.PhoneNumber)
+	if !ok || !h.checkBlockedPrefix(c, phoneNumber) {
+		return
+	}
+	if !h.verifyCaptcha(c, args.Captcha) {
+		return
+	}
+	err := h.SMSCode.SendVoice(phoneNumber)
+	if err != nil {
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(err))
+		return
+	}
+	c.JSON(http.StatusOK, nil)
+}
+
+// normalizePhoneNumberOrError 归一化请求中的手机号并校验，不合法时返回422 并中止请求。
+// 所有接收手机号的接口都应经过这里，保证发送验证码与登录使用同样格式的手机号。
+func normalizePhoneNumberOrError(c *gin.Context, field string, phoneNumber string) (string, bool) {
+	normalized := normalizePhoneNumber(phoneNumber)
+	if !isValidPhoneNumber(normalized) {
+		httpErr := errors.NewHTTPErrorUnprocessableEntity().WithMessage("invalid phone number").WithField(field, "phone")
+		AbortWithHTTPError(c, httpErr)
+		return "", false
+	}
+	return normalized, true
+}
+
+// checkBlockedPrefix 检查已归一化的手机号不属于被屏蔽的号段，否则返回403 并中止请求。
+func (h *AccountHandler) checkBlockedPrefix(c *gin.Context, phoneNumber string) bool {
+	if hasBlockedPrefix(phoneNumber, h.BlockedPrefixes) {
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(errors.NewServerError(errors.ServerErrorPhoneNumberBlocked)))
+		return false
+	}
+	return true
+}
+
+// verifyCaptcha 在开启图形验证码时校验请求携带的验证码，失败时返回错误并中止请求。
+func (h *AccountHandler) verifyCaptcha(c *gin.Context, captcha string) bool {
+	if !h.RequireCaptcha {
+		return true
+	}
+	if captcha == "" {
+		AbortWithHTTPError(c, errors.NewHTTPErrorBadRequest().WithMessage("empty captcha"))
+		return false
+	}
+	if err := h.Captcha.Verify(captcha); err != nil {
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(err))
+		return false
+	}
+	return true
+}
+
 const (
 	// LoginTypeSMSCode 使用短信验证码登录
 	LoginTypeSMSCode = "smscode"
@@ -55,86 +150,119 @@ const (
 func (h *AccountHandler) Login(c *gin.Context) {
 	loginType, ok := c.GetQuery("logintype")
 	if !ok {
-		c.JSON(http.StatusBadRequest, fmt.Errorf("empty login type"))
+		AbortWithHTTPError(c, errors.NewHTTPErrorBadRequest().WithMessage("empty login type"))
 		return
 	}
 	switch loginType {
 	case LoginTypeSMSCode:
 		h.LoginBySMS(c)
 	default:
-		c.JSON(http.StatusBadRequest, fmt.Errorf("login type %s not supported", loginType))
+		AbortWithHTTPError(c, errors.NewHTTPErrorBadRequest().WithMessagef("login type %s not supported", loginType))
 	}
 }
 
 // LoginBySMS 使用手机短信验证码登录。
 func (h *AccountHandler) LoginBySMS(c *gin.Context) {
 	args := protocol.SMSLoginArgs{}
-	err := c.BindJSON(&args)
-	if err != nil {
-		c.AbortWithStatus(http.StatusBadRequest)
+	if !bindJSONOrError(c, &args) {
+		return
+	}
+	phoneNumber, ok := normalizePhoneNumberOrError(c, "phoneNumber", args.PhoneNumber)
+	if !ok {
 		return
 	}
 
-	err = h.SMSCode.Validate(args.PhoneNumber, args.SMSCode)
+	err := h.SMSCode.Validate(phoneNumber, args.SMSCode)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, err)
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(err))
 		return
 	}
-	account, err := h.Account.GetAccountByPhoneNumber(args.PhoneNumber)
-	if err != nil {
-		if err.Error() == "not found" {
-			newAccount := &protocol.Account{
-				ID:          uuid.NewV4().String(),
-				PhoneNumber: args.PhoneNumber,
-			}
-			createErr := h.Account.CreateAccount(newAccount)
-			if createErr != nil {
-				c.JSON(http.StatusUnauthorized, err)
-			}
-			res := &protocol.LoginResponse{
-				ID:       newAccount.ID,
-				Nickname: "",
-			}
-			h.setLoginCookie(c, newAccount)
-			c.JSON(http.StatusOK, res)
+	account, err := h.Account.GetAccountByPhoneNumber(phoneNumber)
+	if errors.IsServerError(err, errors.ServerErrorUserNotFound) {
+		account, err = h.createAccount(phoneNumber, c.ClientIP())
+	}
+	if err == nil && account.Deleted {
+		if time.Since(account.DeleteTime) < h.DeactivateCooldown {
+			AbortWithHTTPError(c, errors.NewHTTPErrorForbidden().WithMessage("account deactivated"))
 			return
 		}
-		c.JSON(http.StatusUnauthorized, err)
+		account, err = h.Account.ReactivateAccount(account.ID)
+	}
+	if err == nil {
+		account, err = h.Account.RecordLogin(account.ID, c.ClientIP(), time.Now())
+	}
+	if err != nil {
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(err))
 		return
 	}
+	token := makeLoginToken(account)
 	res := &protocol.LoginResponse{
 		ID:       account.ID,
 		Nickname: account.Nickname,
+		Token:    token,
 	}
-	h.setLoginCookie(c, account)
+	h.setLoginCookie(c, token)
 	c.JSON(http.StatusOK, res)
 }
 
-// setLoginCookie 设置登录后的cookie。TODO：确定cookie的格式。
-func (h *AccountHandler) setLoginCookie(c *gin.Context, account *protocol.Account) {
-	token := account.ID + "#" + uuid.NewV4().String()
-	c.SetCookie(protocol.LoginCookieKey, token, 0, "/", "qlive.qiniu.com", true, false)
+// createAccount 为首次登录的手机号创建账号。
+// 同一手机号的并发登录可能已经创建了账号，此时返回已创建的账号。
+func (h *AccountHandler) createAccount(phoneNumber string, ip string) (*protocol.Account, error) {
+	newAccount := &protocol.Account{
+		ID:           uuid.NewV4().String(),
+		PhoneNumber:  phoneNumber,
+		RegisterIP:   ip,
+		RegisterTime: time.Now(),
+	}
+	err := h.Account.CreateAccount(newAccount)
+	if errors.IsServerError(err, errors.ServerErrorUserConflict) {
+		return h.Account.GetAccountByPhoneNumber(phoneNumber)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newAccount, nil
+}
+
+// makeLoginToken 生成登录token，格式为 用户ID#token版本#随机串。TODO：确定token的格式。
+func makeLoginToken(account *protocol.Account) string {
+	return account.ID + "#" + strconv.Itoa(account.TokenVersion) + "#" + uuid.NewV4().String()
+}
+
+// setLoginCookie 将登录token 设置在cookie 中。
+func (h *AccountHandler) setLoginCookie(c *gin.Context, token string) {
+	c.SetSameSite(parseSameSite(h.Cookie.SameSite))
+	c.SetCookie(protocol.LoginCookieKey, token, h.Cookie.MaxAgeSecond, "/", h.Cookie.Domain, h.Cookie.Secure, false)
+}
+
+// parseSameSite 将配置中的SameSite 转换为http.SameSite，无法识别时不设置该属性。
+func parseSameSite(sameSite string) http.SameSite {
+	switch strings.ToLower(sameSite) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteDefaultMode
+	}
 }
 
 // UpdateProfile 修改用户信息。
 func (h *AccountHandler) UpdateProfile(c *gin.Context) {
 
-	id := c.GetString(protocol.UserIDContextKey)
+	id := reqctx.GetUserID(c)
 
 	account, err := h.Account.GetAccountByID(id)
-	if err != nil {
+	if err != nil || account.Deleted {
 		httpErr := errors.NewHTTPErrorNotFound().WithMessagef("user %s not found", id)
-		c.JSON(httpErr.Code, httpErr)
-		c.Abort()
+		AbortWithHTTPError(c, httpErr)
 		return
 	}
 
 	args := protocol.UpdateProfileArgs{}
-	bindErr := c.BindJSON(&args)
-	if bindErr != nil {
-		httpErr := errors.NewHTTPErrorBadRequest().WithMessage("invalid args")
-		c.JSON(httpErr.Code, httpErr)
-		c.Abort()
+	if !bindJSONOrError(c, &args) {
 		return
 	}
 
@@ -147,8 +275,7 @@ func (h *AccountHandler) UpdateProfile(c *gin.Context) {
 
 	newAccount, err := h.Account.UpdateAccount(id, account)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
-		c.Abort()
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(err))
 		return
 	}
 	ret := &protocol.UpdateProfileResponse{
@@ -159,8 +286,38 @@ func (h *AccountHandler) UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, ret)
 }
 
+// clearLoginCookie 清除登录cookie。
+func (h *AccountHandler) clearLoginCookie(c *gin.Context) {
+	c.SetSameSite(parseSameSite(h.Cookie.SameSite))
+	c.SetCookie(protocol.LoginCookieKey, "", -1, "/", h.Cookie.Domain, h.Cookie.Secure, false)
+}
+
 // Logout 退出登录。
 func (h *AccountHandler) Logout(c *gin.Context) {
-	c.SetCookie(protocol.LoginCookieKey, "", -1, "/", "qlive.qiniu.com", true, false)
+	h.clearLoginCookie(c)
+	c.JSON(http.StatusOK, nil)
+}
+
+// LogoutAll 使当前账号在所有设备上的登录token 失效并退出登录。
+func (h *AccountHandler) LogoutAll(c *gin.Context) {
+	id := reqctx.GetUserID(c)
+	err := h.Account.RotateTokenVersion(id)
+	if err != nil {
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(err))
+		return
+	}
+	h.clearLoginCookie(c)
+	c.JSON(http.StatusOK, nil)
+}
+
+// DeactivateAccount 注销当前登录的账号并退出登录。
+func (h *AccountHandler) DeactivateAccount(c *gin.Context) {
+	id := reqctx.GetUserID(c)
+	err := h.Account.DeactivateAccount(id)
+	if err != nil {
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(err))
+		return
+	}
+	h.clearLoginCookie(c)
 	c.JSON(http.StatusOK, nil)
 }
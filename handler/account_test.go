@@ -0,0 +1,463 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/qrtc/qlive/config"
+	"github.com/qrtc/qlive/errors"
+	"github.com/qrtc/qlive/protocol"
+)
+
+// newTestAccountHandler 返回使用模拟服务的AccountHandler。
+func newTestAccountHandler() *AccountHandler {
+	return &AccountHandler{
+		Account: &MockAccount{},
+		SMSCode: &MockSMSCode{},
+		Captcha: &MockCaptcha{},
+	}
+}
+
+// newTestAccountEngine 按router 中的方式注册账号相关接口。
+func newTestAccountEngine(h *AccountHandler) *gin.Engine {
+	authHandler := &AuthHandler{
+		Auth: &MockAuth{Account: h.Account},
+	}
+	r := gin.New()
+	r.POST("/login", h.Login)
+	r.GET("/smscode", h.GetSMSCode)
+	r.POST("/send_voice_code", h.SendVoiceCode)
+	r.POST("/profile", authHandler.Authenticate, h.UpdateProfile)
+	r.POST("/logout", authHandler.Authenticate, h.Logout)
+	r.POST("/logout_all", authHandler.Authenticate, h.LogoutAll)
+	r.DELETE("/account", authHandler.Authenticate, h.DeactivateAccount)
+	return r
+}
+
+// login 使用短信验证码登录，返回登录结果与响应状态码，只能在测试goroutine 中调用。
+func login(t *testing.T, r http.Handler, phoneNumber string) (*protocol.LoginResponse, int) {
+	t.Helper()
+	res, code, err := tryLogin(r, phoneNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, code
+}
+
+// tryLogin 与login 相同，但通过返回值报告错误，供测试中启动的goroutine 使用。
+func tryLogin(r http.Handler, phoneNumber string) (*protocol.LoginResponse, int, error) {
+	body, _ := json.Marshal(&protocol.SMSLoginArgs{PhoneNumber: phoneNumber, SMSCode: "123456"})
+	w := doRequest(r, http.MethodPost, "/login?logintype=smscode", string(body), nil)
+	res := &protocol.LoginResponse{}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+			return nil, w.Code, fmt.Errorf("failed to decode login response %q: %v", w.Body.String(), err)
+		}
+	}
+	return res, w.Code, nil
+}
+
+func TestLoginBySMSMissingFields(t *testing.T) {
+	r := newTestAccountEngine(newTestAccountHandler())
+	w := doRequest(r, http.MethodPost, "/login?logintype=smscode", `{}`, nil)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", w.Code, w.Body.String())
+	}
+	httpErr := decodeHTTPError(t, w)
+	want := map[string]string{"phoneNumber": "required", "smsCode": "required"}
+	if len(httpErr.Fields) != len(want) {
+		t.Fatalf("expected fields %v, got %v", want, httpErr.Fields)
+	}
+	for field, rule := range want {
+		if httpErr.Fields[field] != rule {
+			t.Errorf("expected field %s to fail on %s, got %q", field, rule, httpErr.Fields[field])
+		}
+	}
+}
+
+func TestPhoneNumberNormalizedAtEveryEntryPoint(t *testing.T) {
+	r := newTestAccountEngine(newTestAccountHandler())
+	formatted := "+86 138-0000-0000"
+
+	w := doRequest(r, http.MethodGet, "/smscode?number="+url.QueryEscape(formatted), "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected sms code for %q to be sent, got %d: %s", formatted, w.Code, w.Body.String())
+	}
+	first, code := login(t, r, formatted)
+	if code != http.StatusOK {
+		t.Fatalf("expected login with %q to succeed, got %d", formatted, code)
+	}
+	second, code := login(t, r, "13800000000")
+	if code != http.StatusOK {
+		t.Fatalf("expected login with plain number to succeed, got %d", code)
+	}
+	if first.ID != second.ID {
+		t.Errorf("formatted and plain numbers should log in to the same account, got %s and %s", first.ID, second.ID)
+	}
+}
+
+func TestInvalidPhoneNumberRejected(t *testing.T) {
+	r := newTestAccountEngine(newTestAccountHandler())
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		field  string
+	}{
+		{"sms code", http.MethodGet, "/smscode?number=12345", "", "number"},
+		{"letters", http.MethodGet, "/smscode?number=138abc00000000", "", "number"},
+		{"voice code", http.MethodPost, "/send_voice_code", `{"phoneNumber":"1380000000a"}`, "phoneNumber"},
+		{"login", http.MethodPost, "/login?logintype=smscode", `{"phoneNumber":"138000000001","smsCode":"123456"}`, "phoneNumber"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := doRequest(r, tc.method, tc.path, tc.body, nil)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("expected status 422, got %d: %s", w.Code, w.Body.String())
+			}
+			if rule := decodeHTTPError(t, w).Fields[tc.field]; rule != "phone" {
+				t.Errorf("expected field %s to fail on phone, got %q", tc.field, rule)
+			}
+		})
+	}
+}
+
+func TestConcurrentLoginCreatesOneAccount(t *testing.T) {
+	h := newTestAccountHandler()
+	r := newTestAccountEngine(h)
+	const n = 10
+	results := make([]*protocol.LoginResponse, n)
+	codes := make([]int, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], codes[i], errs[i] = tryLogin(r, "13800000000")
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if codes[i] != http.StatusOK {
+			t.Fatalf("expected concurrent login to succeed, got %d", codes[i])
+		}
+		if results[i].ID != results[0].ID {
+			t.Fatalf("expected one account for the phone number, got %s and %s", results[0].ID, results[i].ID)
+		}
+	}
+}
+
+func TestConcurrentLoginAndUpdateProfile(t *testing.T) {
+	r := newTestAccountEngine(newTestAccountHandler())
+	res, code := login(t, r, "13800000000")
+	if code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d", code)
+	}
+	header := map[string]string{"Authorization": "Bearer " + res.Token}
+	const n = 10
+	loginCodes := make([]int, n)
+	loginErrs := make([]error, n)
+	profileCodes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_, loginCodes[i], loginErrs[i] = tryLogin(r, "13800000000")
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			profileCodes[i] = doRequest(r, http.MethodPost, "/profile", `{"nickname":"qlive"}`, header).Code
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		if loginErrs[i] != nil {
+			t.Fatal(loginErrs[i])
+		}
+		if loginCodes[i] != http.StatusOK || profileCodes[i] != http.StatusOK {
+			t.Fatalf("expected login and profile update to succeed, got %d and %d", loginCodes[i], profileCodes[i])
+		}
+	}
+	if res, _ := login(t, r, "13800000000"); res.Nickname != "qlive" {
+		t.Errorf("expected nickname to survive concurrent logins, got %q", res.Nickname)
+	}
+}
+
+func TestDeactivateAccountInvalidatesToken(t *testing.T) {
+	h := newTestAccountHandler()
+	h.DeactivateCooldown = time.Hour
+	r := newTestAccountEngine(h)
+	res, code := login(t, r, "13800000000")
+	if code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d", code)
+	}
+	header := map[string]string{"Authorization": "Bearer " + res.Token}
+	if w := doRequest(r, http.MethodDelete, "/account", "", header); w.Code != http.StatusOK {
+		t.Fatalf("expected deactivation to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := doRequest(r, http.MethodPost, "/profile", `{"nickname":"qlive"}`, header)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected old token to be rejected with 401, got %d: %s", w.Code, w.Body.String())
+	}
+	if errorCode := decodeHTTPError(t, w).ErrorCode; errorCode != errors.ServerErrorInvalidToken {
+		t.Errorf("expected error code %d, got %d", errors.ServerErrorInvalidToken, errorCode)
+	}
+	if _, code := login(t, r, "13800000000"); code != http.StatusForbidden {
+		t.Errorf("expected login during cooldown to be refused with 403, got %d", code)
+	}
+}
+
+func TestReactivatedAccountDoesNotRestoreOldToken(t *testing.T) {
+	h := newTestAccountHandler()
+	r := newTestAccountEngine(h)
+	old, code := login(t, r, "13800000000")
+	if code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d", code)
+	}
+	oldHeader := map[string]string{"Authorization": "Bearer " + old.Token}
+	if w := doRequest(r, http.MethodPost, "/profile", `{"nickname":"qlive"}`, oldHeader); w.Code != http.StatusOK {
+		t.Fatalf("expected profile update to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(r, http.MethodDelete, "/account", "", oldHeader); w.Code != http.StatusOK {
+		t.Fatalf("expected deactivation to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	reactivated, code := login(t, r, "13800000000")
+	if code != http.StatusOK {
+		t.Fatalf("expected login after cooldown to succeed, got %d", code)
+	}
+	if reactivated.ID != old.ID || reactivated.Nickname != "" {
+		t.Errorf("expected account %s to be reactivated with a cleared profile, got %+v", old.ID, reactivated)
+	}
+	if w := doRequest(r, http.MethodPost, "/profile", `{}`, oldHeader); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected token issued before deactivation to stay invalid, got %d", w.Code)
+	}
+	newHeader := map[string]string{"Authorization": "Bearer " + reactivated.Token}
+	if w := doRequest(r, http.MethodPost, "/profile", `{}`, newHeader); w.Code != http.StatusOK {
+		t.Errorf("expected new token to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLoginRecordsRegisterAndLastLogin(t *testing.T) {
+	h := newTestAccountHandler()
+	r := newTestAccountEngine(h)
+	before := time.Now()
+	if _, code := login(t, r, "13800000000"); code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d", code)
+	}
+	account, err := h.Account.GetAccountByPhoneNumber("13800000000")
+	if err != nil {
+		t.Fatalf("expected account to be created: %v", err)
+	}
+	// httptest.NewRequest 使用的客户端地址为192.0.2.1。
+	if account.RegisterIP != "192.0.2.1" || account.LastLoginIP != "192.0.2.1" {
+		t.Errorf("expected register and last login IP to be recorded, got %q and %q", account.RegisterIP, account.LastLoginIP)
+	}
+	if account.RegisterTime.Before(before) || account.LastLoginTime.Before(account.RegisterTime) {
+		t.Errorf("expected register and last login time to be recorded, got %v and %v", account.RegisterTime, account.LastLoginTime)
+	}
+
+	if _, code := login(t, r, "13800000000"); code != http.StatusOK {
+		t.Fatalf("expected second login to succeed, got %d", code)
+	}
+	again, _ := h.Account.GetAccountByPhoneNumber("13800000000")
+	if !again.RegisterTime.Equal(account.RegisterTime) {
+		t.Errorf("expected register time to stay %v, got %v", account.RegisterTime, again.RegisterTime)
+	}
+	if again.LastLoginTime.Before(account.LastLoginTime) {
+		t.Errorf("expected last login time to advance from %v, got %v", account.LastLoginTime, again.LastLoginTime)
+	}
+}
+
+func TestCaptchaBeforeSendingCode(t *testing.T) {
+	testCases := []struct {
+		name           string
+		requireCaptcha bool
+		captcha        string
+		wantCode       int
+		wantErrorCode  int
+	}{
+		{"pass", true, "qlive", http.StatusOK, 0},
+		{"wrong", true, "wrong", http.StatusBadRequest, errors.ServerErrorWrongCaptcha},
+		{"missing", true, "", http.StatusBadRequest, 0},
+		{"disabled", false, "", http.StatusOK, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestAccountHandler()
+			h.RequireCaptcha = tc.requireCaptcha
+			r := newTestAccountEngine(h)
+			requests := []struct {
+				method string
+				path   string
+				body   string
+			}{
+				{http.MethodGet, "/smscode?number=13800000000&captcha=" + url.QueryEscape(tc.captcha), ""},
+				{http.MethodPost, "/send_voice_code", `{"phoneNumber":"13800000000","captcha":"` + tc.captcha + `"}`},
+			}
+			for _, req := range requests {
+				w := doRequest(r, req.method, req.path, req.body, nil)
+				if w.Code != tc.wantCode {
+					t.Fatalf("%s %s: expected status %d, got %d: %s", req.method, req.path, tc.wantCode, w.Code, w.Body.String())
+				}
+				if w.Code == http.StatusOK {
+					continue
+				}
+				if errorCode := decodeHTTPError(t, w).ErrorCode; errorCode != tc.wantErrorCode {
+					t.Errorf("%s %s: expected error code %d, got %d", req.method, req.path, tc.wantErrorCode, errorCode)
+				}
+			}
+		})
+	}
+}
+
+func TestVoiceCodeValidatesForLogin(t *testing.T) {
+	h := newTestAccountHandler()
+	r := newTestAccountEngine(h)
+	w := doRequest(r, http.MethodPost, "/send_voice_code", `{"phoneNumber":"+86 138-0000-0000"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected voice code to be sent, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := h.SMSCode.Validate("13800000000", "123456"); err != nil {
+		t.Fatalf("expected voice code to pass Validate: %v", err)
+	}
+	if _, code := login(t, r, "13800000000"); code != http.StatusOK {
+		t.Errorf("expected login with the voice code to succeed, got %d", code)
+	}
+}
+
+func TestBlockedPrefixRefused(t *testing.T) {
+	h := newTestAccountHandler()
+	h.BlockedPrefixes = []string{"170"}
+	r := newTestAccountEngine(h)
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{"sms code", http.MethodGet, "/smscode?number=17000000000", "", http.StatusForbidden},
+		{"sms code formatted", http.MethodGet, "/smscode?number=" + url.QueryEscape("+86 170-0000-0000"), "", http.StatusForbidden},
+		{"voice code", http.MethodPost, "/send_voice_code", `{"phoneNumber":"17000000000"}`, http.StatusForbidden},
+		{"voice code formatted", http.MethodPost, "/send_voice_code", `{"phoneNumber":"+86 170 0000 0000"}`, http.StatusForbidden},
+		{"not blocked", http.MethodGet, "/smscode?number=13817000000", "", http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := doRequest(r, tc.method, tc.path, tc.body, nil)
+			if w.Code != tc.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusOK {
+				return
+			}
+			if errorCode := decodeHTTPError(t, w).ErrorCode; errorCode != errors.ServerErrorPhoneNumberBlocked {
+				t.Errorf("expected error code %d, got %d", errors.ServerErrorPhoneNumberBlocked, errorCode)
+			}
+		})
+	}
+}
+
+func TestLoginCookieAttributes(t *testing.T) {
+	h := newTestAccountHandler()
+	h.Cookie = config.CookieConfig{Domain: "qlive.example.com", Secure: true, SameSite: "strict", MaxAgeSecond: 3600}
+	r := newTestAccountEngine(h)
+
+	body, _ := json.Marshal(&protocol.SMSLoginArgs{PhoneNumber: "13800000000", SMSCode: "123456"})
+	w := doRequest(r, http.MethodPost, "/login?logintype=smscode", string(body), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	res := &protocol.LoginResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+		t.Fatalf("failed to decode login response: %v", err)
+	}
+	cookie := findCookie(t, w, protocol.LoginCookieKey)
+	// gin 写入cookie 时对值做URL 编码，读取时解码。
+	if value, _ := url.QueryUnescape(cookie.Value); value != res.Token || cookie.MaxAge != 3600 {
+		t.Errorf("expected cookie with the login token and max age 3600, got %+v", cookie)
+	}
+	checkCookieAttributes(t, cookie)
+
+	header := map[string]string{"Authorization": "Bearer " + res.Token}
+	for _, path := range []string{"/logout", "/logout_all"} {
+		w = doRequest(r, http.MethodPost, path, "", header)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		cookie = findCookie(t, w, protocol.LoginCookieKey)
+		if cookie.Value != "" || cookie.MaxAge >= 0 {
+			t.Errorf("%s: expected cookie to be cleared, got %+v", path, cookie)
+		}
+		checkCookieAttributes(t, cookie)
+	}
+}
+
+// findCookie 返回响应中设置的名为name 的cookie。
+func findCookie(t *testing.T, w *httptest.ResponseRecorder, name string) *http.Cookie {
+	t.Helper()
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	t.Fatalf("response does not set cookie %s: %v", name, w.Header()["Set-Cookie"])
+	return nil
+}
+
+// checkCookieAttributes 检查登录cookie 使用了TestLoginCookieAttributes 中的配置。
+func checkCookieAttributes(t *testing.T, cookie *http.Cookie) {
+	t.Helper()
+	if cookie.Domain != "qlive.example.com" || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode || cookie.Path != "/" {
+		t.Errorf("cookie does not reflect the cookie config: %+v", cookie)
+	}
+}
+
+func TestLogoutAllInvalidatesTokens(t *testing.T) {
+	r := newTestAccountEngine(newTestAccountHandler())
+	first, code := login(t, r, "13800000000")
+	if code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d", code)
+	}
+	second, code := login(t, r, "13800000000")
+	if code != http.StatusOK {
+		t.Fatalf("expected second login to succeed, got %d", code)
+	}
+	header := map[string]string{"Authorization": "Bearer " + first.Token}
+	if w := doRequest(r, http.MethodPost, "/logout_all", "", header); w.Code != http.StatusOK {
+		t.Fatalf("expected logout_all to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, token := range []string{first.Token, second.Token} {
+		w := doRequest(r, http.MethodPost, "/profile", `{}`, map[string]string{"Authorization": "Bearer " + token})
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected token issued before logout_all to be rejected, got %d: %s", w.Code, w.Body.String())
+		}
+		if errorCode := decodeHTTPError(t, w).ErrorCode; errorCode != errors.ServerErrorInvalidToken {
+			t.Errorf("expected error code %d, got %d", errors.ServerErrorInvalidToken, errorCode)
+		}
+	}
+
+	third, code := login(t, r, "13800000000")
+	if code != http.StatusOK {
+		t.Fatalf("expected login after logout_all to succeed, got %d", code)
+	}
+	if w := doRequest(r, http.MethodPost, "/profile", `{}`, map[string]string{"Authorization": "Bearer " + third.Token}); w.Code != http.StatusOK {
+		t.Errorf("expected new token to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+}
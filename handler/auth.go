@@ -1,12 +1,13 @@
 package handler
 
 import (
-	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/qrtc/qlive/errors"
 	"github.com/qrtc/qlive/protocol"
+	"github.com/qrtc/qlive/reqctx"
 )
 
 // AuthHandler 处理请求鉴权的需求。
@@ -19,23 +20,39 @@ type AuthInterface interface {
 	GetIDByToken(token string) (id string, err error)
 }
 
+const bearerPrefix = "Bearer "
+
+// getLoginToken 获取请求携带的登录token，按以下顺序查找，使用第一个非空的值：
+// 1. Authorization: Bearer <token> 请求头；
+// 2. 登录cookie；
+// 3. token 查询参数。
+func getLoginToken(c *gin.Context) string {
+	authorization := c.GetHeader("Authorization")
+	if len(authorization) >= len(bearerPrefix) && strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+		// 只有空白的Bearer 视为未携带，继续查找cookie 与查询参数。
+		if token := strings.TrimSpace(authorization[len(bearerPrefix):]); token != "" {
+			return token
+		}
+	}
+	if token, err := c.Cookie(protocol.LoginCookieKey); err == nil && token != "" {
+		return token
+	}
+	return c.Query(protocol.LoginTokenQueryKey)
+}
+
 // Authenticate 校验请求者的身份。
 func (h *AuthHandler) Authenticate(c *gin.Context) {
 
-	token, err := c.Cookie(protocol.LoginCookieKey)
-	if err != nil {
-		httpError := errors.NewHTTPErrorUnauthorized().WithMessage("login cookie not found")
-		c.JSON(http.StatusUnauthorized, httpError)
-		c.Abort()
+	token := getLoginToken(c)
+	if token == "" {
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(errors.NewServerError(errors.ServerErrorNotLoggedIn)))
 		return
 	}
 	id, err := h.Auth.GetIDByToken(token)
 
 	if err != nil {
-		httpError := errors.NewHTTPErrorUnauthorized().WithMessage("failed to authenticate with token")
-		c.JSON(http.StatusUnauthorized, httpError)
-		c.Abort()
+		AbortWithHTTPError(c, errors.NewHTTPErrorByServerError(errors.NewServerError(errors.ServerErrorInvalidToken)))
 		return
 	}
-	c.Set(protocol.UserIDContextKey, id)
+	reqctx.SetUserID(c, id)
 }
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/qrtc/qlive/errors"
+	"github.com/qrtc/qlive/protocol"
+	"github.com/qrtc/qlive/reqctx"
+)
+
+// newTestAuthEngine 返回经过Authenticate 后输出用户ID 的engine，以及已创建的账号。
+func newTestAuthEngine(t *testing.T) (*gin.Engine, *protocol.Account) {
+	t.Helper()
+	accounts := &MockAccount{}
+	account := &protocol.Account{ID: "user", PhoneNumber: "13800000000", TokenVersion: 1}
+	if err := accounts.CreateAccount(account); err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	authHandler := &AuthHandler{Auth: &MockAuth{Account: accounts}}
+	r := gin.New()
+	r.GET("/me", authHandler.Authenticate, func(c *gin.Context) {
+		c.String(http.StatusOK, reqctx.GetUserID(c))
+	})
+	return r, account
+}
+
+func TestAuthenticate(t *testing.T) {
+	r, account := newTestAuthEngine(t)
+	testCases := []struct {
+		name          string
+		token         string
+		wantCode      int
+		wantErrorCode int
+	}{
+		{"missing token", "", http.StatusUnauthorized, errors.ServerErrorNotLoggedIn},
+		{"malformed token", "user", http.StatusUnauthorized, errors.ServerErrorInvalidToken},
+		{"malformed version", "user#x#uuid", http.StatusUnauthorized, errors.ServerErrorInvalidToken},
+		{"unknown user", "nobody#1#uuid", http.StatusUnauthorized, errors.ServerErrorInvalidToken},
+		{"expired version", "user#0#uuid", http.StatusUnauthorized, errors.ServerErrorInvalidToken},
+		{"valid token", makeLoginToken(account), http.StatusOK, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var header map[string]string
+			if tc.token != "" {
+				header = map[string]string{"Authorization": "Bearer " + tc.token}
+			}
+			w := doRequest(r, http.MethodGet, "/me", "", header)
+			if w.Code != tc.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusOK {
+				if id := w.Body.String(); id != account.ID {
+					t.Errorf("expected user ID %s to be set, got %q", account.ID, id)
+				}
+				return
+			}
+			if errorCode := decodeHTTPError(t, w).ErrorCode; errorCode != tc.wantErrorCode {
+				t.Errorf("expected error code %d, got %d", tc.wantErrorCode, errorCode)
+			}
+		})
+	}
+}
+
+func TestLoginTokenSources(t *testing.T) {
+	r, account := newTestAuthEngine(t)
+	valid := makeLoginToken(account)
+	testCases := []struct {
+		name          string
+		authorization string
+		cookie        string
+		query         string
+		wantCode      int
+	}{
+		{"header", "Bearer " + valid, "", "", http.StatusOK},
+		{"lowercase scheme", "bearer " + valid, "", "", http.StatusOK},
+		{"cookie", "", valid, "", http.StatusOK},
+		{"query", "", "", valid, http.StatusOK},
+		{"blank bearer falls back to cookie", "Bearer   ", valid, "", http.StatusOK},
+		{"blank bearer falls back to query", "Bearer ", "", valid, http.StatusOK},
+		{"header wins over cookie", "Bearer user#0#uuid", valid, "", http.StatusUnauthorized},
+		{"cookie wins over query", "", "user#0#uuid", valid, http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := map[string]string{}
+			if tc.authorization != "" {
+				header["Authorization"] = tc.authorization
+			}
+			if tc.cookie != "" {
+				header["Cookie"] = (&http.Cookie{Name: protocol.LoginCookieKey, Value: tc.cookie}).String()
+			}
+			path := "/me"
+			if tc.query != "" {
+				path += "?" + protocol.LoginTokenQueryKey + "=" + url.QueryEscape(tc.query)
+			}
+			w := doRequest(r, http.MethodGet, path, "", header)
+			if w.Code != tc.wantCode {
+				t.Errorf("expected status %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/qrtc/qlive/errors"
+)
+
+func init() {
+	// 校验错误中使用json 字段名，与客户端看到的参数名一致。
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// bindJSONOrError 解析请求体中的JSON参数，失败时返回统一格式的错误并中止请求。
+// 参数未通过binding 标签的校验时返回422，并列出校验失败的字段；读取请求体时的服务内部错误（如请求体过大）按错误码转换。
+func bindJSONOrError(c *gin.Context, args interface{}) bool {
+	err := c.ShouldBindJSON(args)
+	if err == nil {
+		return true
+	}
+	var httpErr *errors.HTTPError
+	if _, ok := err.(*errors.ServerError); ok {
+		httpErr = errors.NewHTTPErrorByServerError(err)
+	} else if validationErrs, ok := err.(validator.ValidationErrors); ok {
+		httpErr = errors.NewHTTPErrorUnprocessableEntity().WithMessage("invalid args in request body")
+		for _, fieldErr := range validationErrs {
+			httpErr.WithField(fieldErr.Field(), fieldErr.Tag())
+		}
+	} else {
+		httpErr = errors.NewHTTPErrorBadRequest().WithMessagef("invalid args in request body: %v", err)
+	}
+	AbortWithHTTPError(c, httpErr)
+	return false
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type bindTestArgs struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func newBindTestEngine() *gin.Engine {
+	r := gin.New()
+	r.POST("/bind", func(c *gin.Context) {
+		args := bindTestArgs{}
+		if !bindJSONOrError(c, &args) {
+			return
+		}
+		c.JSON(http.StatusOK, args)
+	})
+	return r
+}
+
+func TestBindJSONOrErrorBody(t *testing.T) {
+	w := doRequest(newBindTestEngine(), http.MethodPost, "/bind", `{"name":`, map[string]string{"Accept-Language": "en"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode body %q: %v", w.Body.String(), err)
+	}
+	for _, key := range []string{"code", "requestID", "summary", "message"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("error body %q is missing key %q", w.Body.String(), key)
+		}
+	}
+	httpErr := decodeHTTPError(t, w)
+	if httpErr.Code != http.StatusBadRequest || httpErr.Summary != "bad request" {
+		t.Errorf("unexpected error %+v", httpErr)
+	}
+}
+
+func TestBindJSONOrErrorSuccess(t *testing.T) {
+	w := doRequest(newBindTestEngine(), http.MethodPost, "/bind", `{"name":"qlive"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/qrtc/qlive/errors"
+	"github.com/qrtc/qlive/reqctx"
+)
+
+// AbortWithHTTPError 按请求的Accept-Language 本地化错误摘要，填入请求ID 后返回错误并中止请求。
+func AbortWithHTTPError(c *gin.Context, httpErr *errors.HTTPError) {
+	httpErr.Localize(errors.ParseAcceptLanguage(c.GetHeader("Accept-Language")))
+	httpErr.RequestID = reqctx.GetRequestID(c)
+	c.JSON(httpErr.Code, httpErr)
+	c.Abort()
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/qrtc/qlive/errors"
+)
+
+func TestAbortWithHTTPErrorLocalizesSummary(t *testing.T) {
+	r := gin.New()
+	r.GET("/err", func(c *gin.Context) {
+		AbortWithHTTPError(c, errors.NewHTTPErrorUnauthorized())
+	})
+	testCases := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"zh-CN", "未授权"},
+		{"en-US", "unauthorized"},
+		{"", "未授权"},
+	}
+	for _, tc := range testCases {
+		w := doRequest(r, http.MethodGet, "/err", "", map[string]string{"Accept-Language": tc.acceptLanguage})
+		if got := decodeHTTPError(t, w).Summary; got != tc.want {
+			t.Errorf("Accept-Language %q: expected summary %q, got %q", tc.acceptLanguage, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/qrtc/qlive/errors"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// doRequest 向r 发送请求并返回响应。
+func doRequest(r http.Handler, method string, path string, body string, header map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decodeHTTPError 将响应体解析为HTTPError。
+func decodeHTTPError(t *testing.T, w *httptest.ResponseRecorder) *errors.HTTPError {
+	t.Helper()
+	httpErr := &errors.HTTPError{}
+	if err := json.Unmarshal(w.Body.Bytes(), httpErr); err != nil {
+		t.Fatalf("failed to decode error body %q: %v", w.Body.String(), err)
+	}
+	return httpErr
+}
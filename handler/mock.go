@@ -1,52 +1,68 @@
 package handler
 
 import (
-	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/qrtc/qlive/errors"
 	"github.com/qrtc/qlive/protocol"
 )
 
-// MockAccount 模拟的账号服务。
+// MockAccount 模拟的账号服务。返回的账号均为副本，调用方修改后需通过UpdateAccount 保存。
 type MockAccount struct {
+	lock     sync.RWMutex
 	accounts []*protocol.Account
 }
 
 func (m *MockAccount) GetAccountByPhoneNumber(phoneNumber string) (*protocol.Account, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
 	for _, account := range m.accounts {
 		if account.PhoneNumber == phoneNumber {
-			return account, nil
+			copied := *account
+			return &copied, nil
 		}
 	}
-	return nil, fmt.Errorf("not found")
+	return nil, errors.NewServerError(errors.ServerErrorUserNotFound)
 }
 
 func (m *MockAccount) GetAccountByID(id string) (*protocol.Account, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
 	for _, account := range m.accounts {
 		if account.ID == id {
-			return account, nil
+			copied := *account
+			return &copied, nil
 		}
 	}
-	return nil, fmt.Errorf("not found")
+	return nil, errors.NewServerError(errors.ServerErrorUserNotFound)
 }
 
 func (m *MockAccount) CreateAccount(account *protocol.Account) error {
 	if account.ID == "" || account.PhoneNumber == "" {
-		return fmt.Errorf("bad request")
+		return errors.NewServerError(errors.ServerErrorBadRequest)
 	}
+	// 检查与插入在同一把锁内完成，保证手机号唯一。
+	m.lock.Lock()
+	defer m.lock.Unlock()
 	for _, a := range m.accounts {
 		if a.ID == account.ID || a.PhoneNumber == account.PhoneNumber {
-			return fmt.Errorf("conflict")
+			return errors.NewServerError(errors.ServerErrorUserConflict)
 		}
 	}
-	m.accounts = append(m.accounts, account)
+	stored := *account
+	m.accounts = append(m.accounts, &stored)
 	return nil
 }
 
 func (m *MockAccount) UpdateAccount(id string, account *protocol.Account) (*protocol.Account, error) {
 	if account.ID != "" && account.ID != id {
-		return nil, fmt.Errorf("bad request")
+		return nil, errors.NewServerError(errors.ServerErrorBadRequest)
 	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
 	var oldAccount *protocol.Account
 	for _, a := range m.accounts {
 		if a.ID == id {
@@ -55,40 +71,136 @@ func (m *MockAccount) UpdateAccount(id string, account *protocol.Account) (*prot
 		}
 	}
 	if oldAccount == nil {
-		return nil, fmt.Errorf("not found")
+		return nil, errors.NewServerError(errors.ServerErrorUserNotFound)
 	}
 	if account.PhoneNumber != "" && account.PhoneNumber != oldAccount.PhoneNumber {
-		return nil, fmt.Errorf("bad request")
+		return nil, errors.NewServerError(errors.ServerErrorBadRequest)
 	}
 	oldAccount.Nickname = account.Nickname
 	oldAccount.Gender = account.Gender
-	return oldAccount, nil
+	updated := *oldAccount
+	return &updated, nil
+}
+
+func (m *MockAccount) RecordLogin(id string, ip string, loginTime time.Time) (*protocol.Account, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, a := range m.accounts {
+		if a.ID == id {
+			a.LastLoginIP = ip
+			a.LastLoginTime = loginTime
+			updated := *a
+			return &updated, nil
+		}
+	}
+	return nil, errors.NewServerError(errors.ServerErrorUserNotFound)
+}
+
+func (m *MockAccount) DeactivateAccount(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, a := range m.accounts {
+		if a.ID == id {
+			a.Deleted = true
+			a.DeleteTime = time.Now()
+			a.TokenVersion++
+			return nil
+		}
+	}
+	return errors.NewServerError(errors.ServerErrorUserNotFound)
+}
+
+func (m *MockAccount) ReactivateAccount(id string) (*protocol.Account, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, a := range m.accounts {
+		if a.ID == id {
+			a.Nickname = ""
+			a.Gender = ""
+			a.Deleted = false
+			a.DeleteTime = time.Time{}
+			reactivated := *a
+			return &reactivated, nil
+		}
+	}
+	return nil, errors.NewServerError(errors.ServerErrorUserNotFound)
+}
+
+func (m *MockAccount) RotateTokenVersion(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, a := range m.accounts {
+		if a.ID == id {
+			a.TokenVersion++
+			return nil
+		}
+	}
+	return errors.NewServerError(errors.ServerErrorUserNotFound)
+}
+
+func (m *MockAccount) ValidateTokenVersion(id string, version int) error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for _, a := range m.accounts {
+		if a.ID == id {
+			if a.Deleted || a.TokenVersion != version {
+				return errors.NewServerError(errors.ServerErrorInvalidToken)
+			}
+			return nil
+		}
+	}
+	return errors.NewServerError(errors.ServerErrorUserNotFound)
 }
 
 // MockSMSCode 模拟的短信服务。
 type MockSMSCode struct{}
 
-// Send 模拟发送验证码
+// Send 模拟发送验证码。
 func (m *MockSMSCode) Send(phoneNumber string) error {
 	return nil
 }
 
+// SendVoice 模拟通过语音电话发送验证码。
+func (m *MockSMSCode) SendVoice(phoneNumber string) error {
+	return m.Send(phoneNumber)
+}
+
 // Validate 模拟检查输入的验证码。
 func (m *MockSMSCode) Validate(phoneNumber string, smsCode string) error {
 	if smsCode == "123456" {
 		return nil
 	}
-	return fmt.Errorf("wrong sms code")
+	return errors.NewServerError(errors.ServerErrorWrongSMSCode)
+}
+
+// MockCaptcha 模拟的图形验证码服务。
+type MockCaptcha struct{}
+
+// Verify 模拟校验图形验证码。
+func (m *MockCaptcha) Verify(captcha string) error {
+	if captcha == "qlive" {
+		return nil
+	}
+	return errors.NewServerError(errors.ServerErrorWrongCaptcha)
 }
 
 // MockAuth 模拟的认证服务。
-type MockAuth struct{}
+type MockAuth struct {
+	Account AccountInterface
+}
 
-// GetIDByToken 从token 中获取用户ID。
+// GetIDByToken 从token 中获取用户ID，并检查账号未注销、token 版本与账号当前的版本一致。
 func (m *MockAuth) GetIDByToken(token string) (string, error) {
-	parts := strings.SplitN(token, "#", 2)
-	if len(parts) < 2 {
-		return "", fmt.Errorf("invalid token")
+	parts := strings.SplitN(token, "#", 3)
+	if len(parts) < 3 {
+		return "", errors.NewServerError(errors.ServerErrorInvalidToken)
+	}
+	version, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", errors.NewServerError(errors.ServerErrorInvalidToken)
+	}
+	if err := m.Account.ValidateTokenVersion(parts[0], version); err != nil {
+		return "", errors.NewServerError(errors.ServerErrorInvalidToken)
 	}
 	return parts[0], nil
 }
@@ -0,0 +1,43 @@
+package handler
+
+import "strings"
+
+// normalizePhoneNumber 去掉手机号中的空格、横线、括号等分隔符以及+86 国家码，其余字符保留，由调用方校验。
+func normalizePhoneNumber(phoneNumber string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(phoneNumber) {
+		switch r {
+		case ' ', '-', '(', ')':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimPrefix(b.String(), "+86")
+}
+
+// phoneNumberLength 归一化后手机号的位数。
+const phoneNumberLength = 11
+
+// isValidPhoneNumber 判断归一化后的手机号是否为11位数字。
+func isValidPhoneNumber(normalized string) bool {
+	if len(normalized) != phoneNumberLength {
+		return false
+	}
+	for _, r := range normalized {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// hasBlockedPrefix 判断已归一化的手机号是否以被屏蔽的号段开头，配置中的号段允许带分隔符或+86。
+func hasBlockedPrefix(normalized string, blockedPrefixes []string) bool {
+	for _, prefix := range blockedPrefixes {
+		p := normalizePhoneNumber(prefix)
+		if p != "" && strings.HasPrefix(normalized, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package handler
+
+import "testing"
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+		valid bool
+	}{
+		{"13800000000", "13800000000", true},
+		{" 13800000000 ", "13800000000", true},
+		{"+8613800000000", "13800000000", true},
+		{"+86 138 0000 0000", "13800000000", true},
+		{"138-0000-0000", "13800000000", true},
+		{"+86 (138) 0000-0000", "13800000000", true},
+		{"1380000000", "1380000000", false},
+		{"138000000001", "138000000001", false},
+		{"1380000000a", "1380000000a", false},
+		{"", "", false},
+	}
+	for _, tc := range testCases {
+		got := normalizePhoneNumber(tc.input)
+		if got != tc.want {
+			t.Errorf("normalizePhoneNumber(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+		if valid := isValidPhoneNumber(got); valid != tc.valid {
+			t.Errorf("isValidPhoneNumber(%q) = %v, want %v", got, valid, tc.valid)
+		}
+	}
+}
+
+func TestHasBlockedPrefix(t *testing.T) {
+	blockedPrefixes := []string{"170", "+86 171"}
+	testCases := []struct {
+		phoneNumber string
+		want        bool
+	}{
+		{"17000000000", true},
+		{"17100000000", true},
+		{"13800000000", false},
+		{"13817000000", false},
+	}
+	for _, tc := range testCases {
+		if got := hasBlockedPrefix(tc.phoneNumber, blockedPrefixes); got != tc.want {
+			t.Errorf("hasBlockedPrefix(%q) = %v, want %v", tc.phoneNumber, got, tc.want)
+		}
+	}
+	if hasBlockedPrefix("17000000000", nil) {
+		t.Error("expected no prefix to be blocked without configuration")
+	}
+}
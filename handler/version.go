@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/qrtc/qlive/protocol"
+	"github.com/qrtc/qlive/version"
+)
+
+// GetVersion 返回当前运行服务的构建信息。
+func GetVersion(c *gin.Context) {
+	res := &protocol.VersionResponse{
+		Version:   version.Version,
+		GitCommit: version.GitCommit,
+		BuildTime: version.BuildTime,
+	}
+	c.JSON(http.StatusOK, res)
+}
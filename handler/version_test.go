@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/qrtc/qlive/version"
+)
+
+func TestGetVersion(t *testing.T) {
+	oldVersion, oldGitCommit, oldBuildTime := version.Version, version.GitCommit, version.BuildTime
+	defer func() {
+		version.Version, version.GitCommit, version.BuildTime = oldVersion, oldGitCommit, oldBuildTime
+	}()
+	version.Version = "v1.0.0"
+	version.GitCommit = "abc123"
+	version.BuildTime = "2020-01-01T00:00:00Z"
+
+	r := gin.New()
+	r.GET("/version", GetVersion)
+	w := doRequest(r, http.MethodGet, "/version", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	res := map[string]string{}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to decode version response %q: %v", w.Body.String(), err)
+	}
+	want := map[string]string{
+		"version":   "v1.0.0",
+		"gitCommit": "abc123",
+		"buildTime": "2020-01-01T00:00:00Z",
+	}
+	if len(res) != len(want) {
+		t.Errorf("expected fields %v, got %v", want, res)
+	}
+	for field, value := range want {
+		if res[field] != value {
+			t.Errorf("expected %s to be %q, got %q", field, value, res[field])
+		}
+	}
+}
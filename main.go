@@ -1,12 +1,46 @@
 package main
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/qrtc/qlive/config"
 	"github.com/qrtc/qlive/router"
 )
 
 func main() {
-	r := router.NewRouter()
 	cfg := config.NewSample()
-	r.Run(cfg.ListenAddr)
+	r := router.NewRouter(cfg)
+	hs := &http.Server{
+		Addr:    cfg.ListenAddr,
+		Handler: r,
+	}
+
+	go func() {
+		if err := hs.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("failed to listen on %s: %v", cfg.ListenAddr, err)
+		}
+	}()
+
+	// 收到SIGINT/SIGTERM 后停止接受新请求，等待已有请求处理完成后退出。
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	log.Printf("received signal %v, shutting down", sig)
+
+	shutdownTimeoutSecond := cfg.ShutdownTimeoutSecond
+	if shutdownTimeoutSecond <= 0 {
+		shutdownTimeoutSecond = config.DefaultShutdownTimeoutSecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(shutdownTimeoutSecond)*time.Second)
+	defer cancel()
+	if err := hs.Shutdown(ctx); err != nil {
+		log.Printf("graceful shutdown timed out, force closing: %v", err)
+		hs.Close()
+	}
 }
@@ -28,6 +28,12 @@ type Account struct {
 	LastLoginIP string `json:"lastLoginIP" bson:"lastLoginIP"`
 	// LastLoginTime 上次登录时间。
 	LastLoginTime time.Time `json:"lastLoginTime" bson:"lastLoginTime"`
+	// TokenVersion 登录token 版本，登录token 中携带该版本，版本变更后之前签发的token 全部失效。
+	TokenVersion int `json:"tokenVersion" bson:"tokenVersion"`
+	// Deleted 账号是否已被用户注销。
+	Deleted bool `json:"deleted" bson:"deleted"`
+	// DeleteTime 账号注销时间。
+	DeleteTime time.Time `json:"deleteTime,omitempty" bson:"deleteTime,omitempty"`
 }
 
 // UserStatus 用户的当前状态。
@@ -4,10 +4,17 @@ package protocol
 	protocol.go: 规定API的参数与返回值的定义，***Args 表示 *** 接口的参数，***Response表示 *** 接口的返回体格式。
 */
 
-// SMSLoginArgs 通过短信登录的参数
+// SMSLoginArgs 通过短信登录的参数。手机号可包含空格、横线及+86，由服务端归一化后校验。
 type SMSLoginArgs struct {
-	PhoneNumber string `json:"phoneNumber"`
-	SMSCode     string `json:"smsCode"`
+	PhoneNumber string `json:"phoneNumber" binding:"required"`
+	SMSCode     string `json:"smsCode" binding:"required,numeric,max=8"`
+}
+
+// SendVoiceCodeArgs 发送语音验证码的参数。
+type SendVoiceCodeArgs struct {
+	PhoneNumber string `json:"phoneNumber" binding:"required"`
+	// Captcha 开启图形验证码校验时需要提供。
+	Captcha string `json:"captcha"`
 }
 
 // LoginResponse 登录的返回结果。
@@ -15,18 +22,20 @@ type LoginResponse struct {
 	ID       string `json:"id"`
 	Nickname string `json:"nickname"`
 	Gender   string `json:"gender"`
+	// Token 登录token，非浏览器客户端可通过Authorization 请求头携带。
+	Token string `json:"token"`
 }
 
 // LoginCookieKey 登录用的token，存放在cookie中。
 const LoginCookieKey = "qlive-login-token"
 
-// UserIDContextKey 存放在请求context 中的用户ID。
-const UserIDContextKey = "userID"
+// LoginTokenQueryKey 通过查询参数携带登录token 时使用的参数名。
+const LoginTokenQueryKey = "token"
 
 // UpdateProfileArgs 修改用户信息接口。
 type UpdateProfileArgs struct {
-	Nickname string `json:"nickname"`
-	Gender   string `json:"gender"`
+	Nickname string `json:"nickname" binding:"max=32"`
+	Gender   string `json:"gender" binding:"max=16"`
 }
 
 // UpdateProfileResponse 修改用户信息的返回结果。
@@ -35,3 +44,10 @@ type UpdateProfileResponse struct {
 	Nickname string `json:"nickname"`
 	Gender   string `json:"gender"`
 }
+
+// VersionResponse 服务构建信息。
+type VersionResponse struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildTime string `json:"buildTime"`
+}
@@ -0,0 +1,66 @@
+package reqctx
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+	reqctx.go: 统一存取请求context 中的数据，handler 与中间件均通过这里的函数读写，不直接使用key。
+*/
+
+const (
+	userIDKey       = "qlive.userID"
+	requestIDKey    = "qlive.requestID"
+	loggerKey       = "qlive.logger"
+	requestStartKey = "qlive.requestStart"
+)
+
+// defaultLogger 请求context 中没有logger 时使用。
+var defaultLogger = log.New(os.Stderr, "", log.LstdFlags)
+
+// SetUserID 保存鉴权通过的用户ID。
+func SetUserID(c *gin.Context, id string) {
+	c.Set(userIDKey, id)
+}
+
+// GetUserID 返回鉴权通过的用户ID，未鉴权时返回空字符串。
+func GetUserID(c *gin.Context) string {
+	return c.GetString(userIDKey)
+}
+
+// SetRequestID 保存请求ID。
+func SetRequestID(c *gin.Context, requestID string) {
+	c.Set(requestIDKey, requestID)
+}
+
+// GetRequestID 返回请求ID。
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// SetLogger 保存本次请求使用的logger。
+func SetLogger(c *gin.Context, logger *log.Logger) {
+	c.Set(loggerKey, logger)
+}
+
+// GetLogger 返回本次请求使用的logger，没有设置时返回默认logger。
+func GetLogger(c *gin.Context) *log.Logger {
+	if logger, ok := c.Value(loggerKey).(*log.Logger); ok {
+		return logger
+	}
+	return defaultLogger
+}
+
+// SetRequestStart 保存请求开始处理的时间。
+func SetRequestStart(c *gin.Context, start time.Time) {
+	c.Set(requestStartKey, start)
+}
+
+// GetRequestStart 返回请求开始处理的时间，没有设置时返回零值。
+func GetRequestStart(c *gin.Context) time.Time {
+	return c.GetTime(requestStartKey)
+}
@@ -0,0 +1,58 @@
+package reqctx
+
+import (
+	"bytes"
+	"log"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return c
+}
+
+func TestUnsetValues(t *testing.T) {
+	c := newTestContext()
+	if id := GetUserID(c); id != "" {
+		t.Errorf("expected empty user ID, got %q", id)
+	}
+	if requestID := GetRequestID(c); requestID != "" {
+		t.Errorf("expected empty request ID, got %q", requestID)
+	}
+	if logger := GetLogger(c); logger != defaultLogger {
+		t.Errorf("expected default logger, got %v", logger)
+	}
+	if start := GetRequestStart(c); !start.IsZero() {
+		t.Errorf("expected zero request start, got %v", start)
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	c := newTestContext()
+	out := &bytes.Buffer{}
+	logger := log.New(out, "", 0)
+	start := time.Now()
+
+	SetUserID(c, "user")
+	SetRequestID(c, "req-123")
+	SetLogger(c, logger)
+	SetRequestStart(c, start)
+
+	if id := GetUserID(c); id != "user" {
+		t.Errorf("expected user ID user, got %q", id)
+	}
+	if requestID := GetRequestID(c); requestID != "req-123" {
+		t.Errorf("expected request ID req-123, got %q", requestID)
+	}
+	GetLogger(c).Print("hello")
+	if out.String() != "hello\n" {
+		t.Errorf("expected log to be written to the request logger, got %q", out.String())
+	}
+	if got := GetRequestStart(c); !got.Equal(start) {
+		t.Errorf("expected request start %v, got %v", start, got)
+	}
+}
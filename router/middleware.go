@@ -0,0 +1,154 @@
+package router
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/qrtc/qlive/config"
+	"github.com/qrtc/qlive/errors"
+	"github.com/qrtc/qlive/handler"
+	"github.com/qrtc/qlive/reqctx"
+)
+
+// RequestIDHeader 返回请求ID的HTTP头。
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 客户端传入的请求ID 的最大长度。
+const maxRequestIDLength = 64
+
+// addRequestID 为每个请求设置请求ID，以及输出到out、带有请求ID前缀的logger。
+// 客户端传入的请求ID 仅在格式合法时沿用，否则由服务端生成。
+func addRequestID(out io.Writer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.NewV4().String()
+		}
+		reqctx.SetRequestID(c, requestID)
+		reqctx.SetLogger(c, log.New(out, "["+requestID+"] ", log.LstdFlags))
+		reqctx.SetRequestStart(c, time.Now())
+		c.Header(RequestIDHeader, requestID)
+	}
+}
+
+// isValidRequestID 请求ID 只允许字母、数字及 "-"、"_"、"."，避免伪造日志行或响应头。
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// logRequest 在请求处理完成后输出访问日志，包括方法、路径、状态码、耗时、用户ID与请求ID。
+func logRequest(c *gin.Context) {
+	c.Next()
+
+	var latency time.Duration
+	if start := reqctx.GetRequestStart(c); !start.IsZero() {
+		latency = time.Since(start)
+	}
+	reqctx.GetLogger(c).Printf("%s %s status=%d latency=%v user=%s requestID=%s",
+		c.Request.Method, c.Request.URL.Path, c.Writer.Status(), latency,
+		reqctx.GetUserID(c), reqctx.GetRequestID(c))
+}
+
+// cors 根据配置为允许的来源设置跨域响应头，并直接响应OPTIONS 预检请求。
+// 来源通过"*"通配允许时返回"*"，且不允许携带凭证，避免任意来源带着登录cookie 访问。
+func cors(conf config.CORSConfig) gin.HandlerFunc {
+	allowMethods := strings.Join(conf.AllowMethods, ", ")
+	allowHeaders := strings.Join(conf.AllowHeaders, ", ")
+	return func(c *gin.Context) {
+		c.Header("Vary", "Origin")
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		switch {
+		case isOriginListed(conf.AllowOrigins, origin):
+			c.Header("Access-Control-Allow-Origin", origin)
+			if conf.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		case isOriginListed(conf.AllowOrigins, "*"):
+			c.Header("Access-Control-Allow-Origin", "*")
+		default:
+			c.Next()
+			return
+		}
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Access-Control-Allow-Methods", allowMethods)
+			c.Header("Access-Control-Allow-Headers", allowHeaders)
+			if conf.MaxAgeSecond > 0 {
+				c.Header("Access-Control-Max-Age", strconv.Itoa(conf.MaxAgeSecond))
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// isOriginListed 判断origin 是否在配置的来源列表中（不区分大小写）。
+func isOriginListed(allowOrigins []string, origin string) bool {
+	for _, allowed := range allowOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitRequestBody 限制请求体的大小，超过limit 字节时返回413。limit 不为正数时不做限制。
+func limitRequestBody(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			httpErr := errors.NewHTTPErrorByServerError(errors.NewServerError(errors.ServerErrorRequestBodyTooLarge))
+			handler.AbortWithHTTPError(c, httpErr.WithMessagef("request body exceeds %d bytes", limit))
+			return
+		}
+		// 未声明长度或长度不实的请求体，在读取超出限制时返回ServerErrorRequestBodyTooLarge。
+		c.Request.Body = &limitedBody{ReadCloser: c.Request.Body, remaining: limit}
+		c.Next()
+	}
+}
+
+// limitedBody 最多读取remaining 字节的请求体，超出时返回服务内部错误，由handler 转换为413。
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// 多读一个字节，用于判断请求体是否超出限制。
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return n, err
+	}
+	n = int(b.remaining)
+	b.remaining = 0
+	return n, errors.NewServerError(errors.ServerErrorRequestBodyTooLarge)
+}
@@ -0,0 +1,170 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/qrtc/qlive/config"
+	"github.com/qrtc/qlive/errors"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newLoggedEngine(out *bytes.Buffer) *gin.Engine {
+	r := gin.New()
+	r.Use(addRequestID(out), logRequest)
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusTeapot, "pong") })
+	return r
+}
+
+func TestLogRequest(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := newLoggedEngine(out)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get(RequestIDHeader); got != "req-123" {
+		t.Fatalf("expected request ID header req-123, got %q", got)
+	}
+	line := out.String()
+	for _, want := range []string{"[req-123] ", "GET /ping", "status=418", "latency=", "requestID=req-123"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("access log %q does not contain %q", line, want)
+		}
+	}
+}
+
+func TestAddRequestIDRejectsInvalidID(t *testing.T) {
+	for _, requestID := range []string{
+		"abc\ninjected line",
+		"abc def",
+		strings.Repeat("a", maxRequestIDLength+1),
+	} {
+		out := &bytes.Buffer{}
+		r := newLoggedEngine(out)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header[RequestIDHeader] = []string{requestID}
+		r.ServeHTTP(w, req)
+
+		got := w.Header().Get(RequestIDHeader)
+		if got == requestID || !isValidRequestID(got) {
+			t.Errorf("request ID %q should be replaced by a generated one, got %q", requestID, got)
+		}
+		if n := strings.Count(out.String(), "\n"); n != 1 {
+			t.Errorf("expected exactly one log line for request ID %q, got %d: %q", requestID, n, out.String())
+		}
+	}
+}
+
+func newCORSEngine(conf config.CORSConfig) *gin.Engine {
+	r := gin.New()
+	g := r.Group("/v1", cors(conf))
+	g.OPTIONS("*path", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	g.GET("hello", func(c *gin.Context) { c.String(http.StatusOK, "hello") })
+	return r
+}
+
+func TestCORS(t *testing.T) {
+	listed := config.CORSConfig{
+		AllowOrigins:     []string{"https://qlive.qiniu.com"},
+		AllowMethods:     []string{"GET", "POST"},
+		AllowHeaders:     []string{"Content-Type"},
+		AllowCredentials: true,
+	}
+	wildcard := listed
+	wildcard.AllowOrigins = []string{"*"}
+
+	testCases := []struct {
+		name            string
+		conf            config.CORSConfig
+		method          string
+		origin          string
+		wantAllowOrigin string
+		wantCredentials string
+		wantCode        int
+	}{
+		{"allowed origin", listed, http.MethodGet, "https://qlive.qiniu.com", "https://qlive.qiniu.com", "true", http.StatusOK},
+		{"allowed origin preflight", listed, http.MethodOptions, "https://qlive.qiniu.com", "https://qlive.qiniu.com", "true", http.StatusNoContent},
+		{"disallowed origin", listed, http.MethodGet, "https://evil.example.com", "", "", http.StatusOK},
+		{"disallowed origin preflight", listed, http.MethodOptions, "https://evil.example.com", "", "", http.StatusNoContent},
+		{"wildcard never allows credentials", wildcard, http.MethodGet, "https://evil.example.com", "*", "", http.StatusOK},
+		{"no origin", listed, http.MethodGet, "", "", "", http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/v1/hello", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			newCORSEngine(tc.conf).ServeHTTP(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("expected status %d, got %d", tc.wantCode, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.wantAllowOrigin {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tc.wantAllowOrigin, got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tc.wantCredentials {
+				t.Errorf("expected Access-Control-Allow-Credentials %q, got %q", tc.wantCredentials, got)
+			}
+			if got := w.Header().Get("Vary"); got != "Origin" {
+				t.Errorf("expected Vary: Origin, got %q", got)
+			}
+		})
+	}
+}
+
+func TestLimitRequestBody(t *testing.T) {
+	r := NewRouter(&config.Config{MaxRequestBodyBytes: 64})
+	small := `{"phoneNumber":"13800000000","smsCode":"123456"}`
+	large := `{"phoneNumber":"13800000000","smsCode":"123456","padding":"` + strings.Repeat("x", 100) + `"}`
+	testCases := []struct {
+		name     string
+		body     string
+		chunked  bool
+		wantCode int
+	}{
+		{"within limit", small, false, http.StatusOK},
+		{"within limit chunked", small, true, http.StatusOK},
+		{"content length", large, false, http.StatusRequestEntityTooLarge},
+		{"chunked", large, true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/v1/login?logintype=smscode", strings.NewReader(tc.body))
+			if tc.chunked {
+				// 未声明长度的请求体只能在读取时检查大小。
+				req.ContentLength = -1
+			}
+			r.ServeHTTP(w, req)
+			if w.Code != tc.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusOK {
+				return
+			}
+			httpErr := &errors.HTTPError{}
+			if err := json.Unmarshal(w.Body.Bytes(), httpErr); err != nil {
+				t.Fatalf("failed to decode error body %q: %v", w.Body.String(), err)
+			}
+			if httpErr.ErrorCode != errors.ServerErrorRequestBodyTooLarge {
+				t.Errorf("expected error code %d, got %d", errors.ServerErrorRequestBodyTooLarge, httpErr.ErrorCode)
+			}
+		})
+	}
+}
@@ -1,28 +1,48 @@
 package router
 
 import (
+	"net/http"
+	"os"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/qrtc/qlive/config"
 	"github.com/qrtc/qlive/handler"
 )
 
 // NewRouter 返回gin router，分流API。
-func NewRouter() *gin.Engine {
+func NewRouter(cfg *config.Config) *gin.Engine {
 	router := gin.New()
+	router.Use(addRequestID(os.Stderr), logRequest)
+	accounts := &handler.MockAccount{}
 	accountHandler := &handler.AccountHandler{
-		Account: &handler.MockAccount{},
-		SMSCode: &handler.MockSMSCode{},
+		Account:            accounts,
+		SMSCode:            &handler.MockSMSCode{},
+		Captcha:            &handler.MockCaptcha{},
+		RequireCaptcha:     cfg.SMS.RequireCaptcha,
+		BlockedPrefixes:    cfg.SMS.BlockedPrefixes,
+		DeactivateCooldown: time.Duration(cfg.Account.DeactivateCooldownHour) * time.Hour,
+		Cookie:             cfg.Cookie,
 	}
 	authHandler := &handler.AuthHandler{
-		Auth: &handler.MockAuth{},
+		Auth: &handler.MockAuth{Account: accounts},
 	}
-	v1 := router.Group("/v1")
+	// 存活检查，供负载均衡使用。
+	router.GET("healthz", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) })
+	v1 := router.Group("/v1", cors(cfg.CORS), limitRequestBody(cfg.MaxRequestBodyBytes))
 	{
+		// 预检请求由cors 中间件处理。
+		v1.OPTIONS("*path", func(c *gin.Context) { c.Status(http.StatusNoContent) })
 		v1.GET("hello", func(c *gin.Context) { c.Writer.WriteString("Hello qiniu") })
+		v1.GET("version", handler.GetVersion)
 		v1.POST("login", accountHandler.Login)
 		v1.GET("smscode", accountHandler.GetSMSCode)
+		v1.POST("send_voice_code", accountHandler.SendVoiceCode)
 		v1.POST("profile", authHandler.Authenticate, accountHandler.UpdateProfile)
 		v1.POST("logout", authHandler.Authenticate, accountHandler.Logout)
+		v1.POST("logout_all", authHandler.Authenticate, accountHandler.LogoutAll)
+		v1.DELETE("account", authHandler.Authenticate, accountHandler.DeactivateAccount)
 	}
 	return router
 }
@@ -0,0 +1,15 @@
+package version
+
+/*
+	version.go: 构建信息，编译时通过 -ldflags 注入，例如：
+	go build -ldflags "-X github.com/qrtc/qlive/version.Version=v1.0.0 -X github.com/qrtc/qlive/version.GitCommit=$(git rev-parse HEAD) -X github.com/qrtc/qlive/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+*/
+
+var (
+	// Version 版本号。
+	Version = "dev"
+	// GitCommit 构建时的git commit。
+	GitCommit = "unknown"
+	// BuildTime 构建时间。
+	BuildTime = "unknown"
+)